@@ -0,0 +1,186 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"mime/multipart"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A FormDecodeError describes a form value that could not be converted
+// to the Go type of the struct field it was decoded into.
+type FormDecodeError struct {
+	Field string
+	Value string
+	Type  reflect.Type
+}
+
+func (e *FormDecodeError) String() string {
+	return "http: cannot decode form value " + strconv.Quote(e.Value) + " into field " + e.Field + " of type " + e.Type.String()
+}
+
+var fileHeaderPtrType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// DecodeForm calls ParseForm and then populates the fields of the
+// struct pointed to by v from the request's form values. A field's form
+// name comes from a "form" struct tag, which has the same syntax as the
+// "json" tag (a name, optionally followed by ",omitempty" and other
+// comma-separated options); a field with no tag is matched by its Go
+// field name. DecodeForm supports bool, int, uint, float, and string
+// fields, and []T slices populated from a form key's repeated values.
+// Fields whose form key is absent are left unchanged.
+func (r *Request) DecodeForm(v interface{}) os.Error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	return decodeForm(r.Form, nil, v)
+}
+
+// DecodeMultipart is DecodeForm extended to multipart/form-data
+// requests: it calls ParseMultipartForm(maxMemory) instead of
+// ParseForm, and in addition to the kinds DecodeForm supports, it
+// populates *multipart.FileHeader and []*multipart.FileHeader fields
+// from the request's uploaded files.
+func (r *Request) DecodeMultipart(v interface{}, maxMemory int64) os.Error {
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		return err
+	}
+	var files map[string][]*multipart.FileHeader
+	if r.MultipartForm != nil {
+		files = r.MultipartForm.File
+	}
+	return decodeForm(r.Form, files, v)
+}
+
+func decodeForm(values map[string][]string, files map[string][]*multipart.FileHeader, v interface{}) os.Error {
+	if v == nil {
+		return os.NewError("http: DecodeForm(nil)")
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return os.NewError("http: DecodeForm(non-pointer " + reflect.TypeOf(v).String() + ")")
+	}
+	sv := rv.Elem()
+	if sv.Kind() != reflect.Struct {
+		return os.NewError("http: DecodeForm requires a pointer to a struct, got " + sv.Type().String())
+	}
+
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		if sf.PkgPath != "" {
+			// unexported field
+			continue
+		}
+		name := formFieldName(sf)
+		if name == "-" {
+			continue
+		}
+		if err := decodeFormField(name, values[name], files[name], sv.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formFieldName returns the form key that sf should be populated from,
+// honoring the "form" tag the same way package json honors "json".
+func formFieldName(sf reflect.StructField) string {
+	tag := sf.Tag.Get("form")
+	if tag == "" {
+		return sf.Name
+	}
+	name := tag
+	if i := strings.Index(tag, ","); i >= 0 {
+		name = tag[:i]
+	}
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
+
+func decodeFormField(name string, vals []string, fileHeaders []*multipart.FileHeader, fv reflect.Value) os.Error {
+	switch fv.Kind() {
+	case reflect.Slice:
+		if fv.Type().Elem() == fileHeaderPtrType {
+			if len(fileHeaders) == 0 {
+				return nil
+			}
+			fv.Set(reflect.MakeSlice(fv.Type(), len(fileHeaders), len(fileHeaders)))
+			for i, fh := range fileHeaders {
+				fv.Index(i).Set(reflect.ValueOf(fh))
+			}
+			return nil
+		}
+		if len(vals) == 0 {
+			return nil
+		}
+		fv.Set(reflect.MakeSlice(fv.Type(), len(vals), len(vals)))
+		for i, s := range vals {
+			if err := decodeFormScalar(name, s, fv.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Ptr:
+		if fv.Type() != fileHeaderPtrType {
+			if len(vals) == 0 {
+				return nil
+			}
+			break
+		}
+		if len(fileHeaders) == 0 {
+			return nil
+		}
+		fv.Set(reflect.ValueOf(fileHeaders[0]))
+		return nil
+
+	default:
+		if len(vals) == 0 {
+			return nil
+		}
+		return decodeFormScalar(name, vals[0], fv)
+	}
+	return &FormDecodeError{name, vals[0], fv.Type()}
+}
+
+func decodeFormScalar(name, s string, fv reflect.Value) os.Error {
+	switch fv.Kind() {
+	case reflect.Bool:
+		b, err := strconv.Atob(s)
+		if err != nil {
+			return &FormDecodeError{name, s, fv.Type()}
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.Atoi64(s)
+		if err != nil {
+			return &FormDecodeError{name, s, fv.Type()}
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.Atoui64(s)
+		if err != nil {
+			return &FormDecodeError{name, s, fv.Type()}
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.Atof64(s)
+		if err != nil {
+			return &FormDecodeError{name, s, fv.Type()}
+		}
+		fv.SetFloat(f)
+	case reflect.String:
+		fv.SetString(s)
+	default:
+		return &FormDecodeError{name, s, fv.Type()}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"mime/multipart"
+	"reflect"
+	"testing"
+
+	. "http"
+)
+
+type decodeFormTarget struct {
+	Name   string
+	Age    int `form:"age"`
+	Active bool
+	Tags   []string
+}
+
+var decodeFormTests = []struct {
+	query string
+	want  decodeFormTarget
+}{
+	{
+		query: "Name=gopher&age=5&Active=true&Tags=a&Tags=b",
+		want:  decodeFormTarget{Name: "gopher", Age: 5, Active: true, Tags: []string{"a", "b"}},
+	},
+	{
+		query: "Name=gopher",
+		want:  decodeFormTarget{Name: "gopher"},
+	},
+}
+
+func TestDecodeForm(t *testing.T) {
+	for i, tt := range decodeFormTests {
+		req := &Request{Method: "GET"}
+		req.URL, _ = ParseURL("http://example.com/?" + tt.query)
+		var got decodeFormTarget
+		if err := req.DecodeForm(&got); err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("test %d: DecodeForm = %+v, want %+v", i, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeFormNil(t *testing.T) {
+	req := &Request{Method: "GET"}
+	req.URL, _ = ParseURL("http://example.com/")
+	if err := req.DecodeForm(nil); err == nil {
+		t.Fatal("DecodeForm(nil): got nil error, want one")
+	}
+}
+
+func TestDecodeFormBadValue(t *testing.T) {
+	type T struct {
+		Age int
+	}
+	req := &Request{Method: "GET"}
+	req.URL, _ = ParseURL("http://example.com/?Age=notanumber")
+	var v T
+	err := req.DecodeForm(&v)
+	if err == nil {
+		t.Fatal("DecodeForm: got nil error, want one")
+	}
+	if _, ok := err.(*FormDecodeError); !ok {
+		t.Fatalf("DecodeForm error = %T, want *FormDecodeError", err)
+	}
+}
+
+type multipartTarget struct {
+	Texta string                  `form:"texta"`
+	Filea *multipart.FileHeader   `form:"filea"`
+	Fileb []*multipart.FileHeader `form:"fileb"`
+}
+
+func TestDecodeMultipart(t *testing.T) {
+	req := newTestMultipartRequest(t)
+	var v multipartTarget
+	if err := req.DecodeMultipart(&v, 25); err != nil {
+		t.Fatal(err)
+	}
+	defer req.MultipartForm.RemoveAll()
+
+	if v.Texta != textaValue {
+		t.Errorf("Texta = %q, want %q", v.Texta, textaValue)
+	}
+	if v.Filea == nil || v.Filea.Filename != "filea.txt" {
+		t.Errorf("Filea = %+v, want Filename filea.txt", v.Filea)
+	}
+	if len(v.Fileb) != 1 || v.Fileb[0].Filename != "fileb.txt" {
+		t.Errorf("Fileb = %+v, want one file named fileb.txt", v.Fileb)
+	}
+}
@@ -0,0 +1,823 @@
+// Copyright 2010 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package json
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal parses the JSON-encoded data and stores the result
+// in the value pointed to by v.
+//
+// Unmarshal uses the inverse of the encodings that Marshal uses,
+// allocating maps, slices, and pointers as necessary, with the following
+// additional rules:
+//
+// To unmarshal JSON into a pointer, Unmarshal first handles the case of
+// the JSON being the JSON literal null.  In that case, Unmarshal sets
+// the pointer to nil.  Otherwise, Unmarshal unmarshals the JSON into
+// the value pointed at by the pointer.  If the pointer is nil, Unmarshal
+// allocates a new value for it to point to.
+//
+// To unmarshal JSON into a value implementing the Unmarshaler interface,
+// Unmarshal calls that value's UnmarshalJSON method, including when the
+// input is the JSON literal null.
+//
+// Otherwise, if the value implements the TextUnmarshaler interface and
+// the JSON value is a string, Unmarshal calls UnmarshalText with the
+// unquoted form of the string.  This is the decode-side counterpart of
+// the TextMarshaler fallback used by Marshal, and lets types such as
+// net.IP round-trip through JSON without a MarshalJSON/UnmarshalJSON
+// pair of their own.
+//
+// To unmarshal a JSON object into a map, Unmarshal first establishes a
+// map to use.  If the map is nil, Unmarshal allocates a new map.
+// Otherwise Unmarshal reuses the existing map, keeping existing entries.
+// The map's key type must either be string or implement TextUnmarshaler.
+//
+// To unmarshal a JSON array into a slice, Unmarshal resets the slice
+// length to zero and then appends each element to the slice.
+//
+// To unmarshal a JSON object into a struct, Unmarshal matches incoming
+// object keys to the keys used by Marshal (either the struct field name
+// or its tag), preferring an exact match but also accepting a
+// case-insensitive one.  Unmarshal ignores keys that don't match any
+// field in the destination.
+func Unmarshal(data []byte, v interface{}) os.Error {
+	d := new(decodeState)
+	d.init(data)
+	return d.unmarshal(v)
+}
+
+// Unmarshaler is the interface implemented by objects that can unmarshal
+// a JSON description of themselves.  The input can be assumed to be a
+// valid encoding of a JSON value.  UnmarshalJSON must copy the JSON data
+// if it wishes to retain the data after returning.
+type Unmarshaler interface {
+	UnmarshalJSON([]byte) os.Error
+}
+
+// TextUnmarshaler is the interface implemented by objects that can
+// unmarshal a textual representation of themselves, as produced by
+// TextMarshaler.  UnmarshalText must be able to decode the form
+// generated by MarshalText.  UnmarshalText must copy the text if it
+// wishes to retain it after returning.
+type TextUnmarshaler interface {
+	UnmarshalText(text []byte) os.Error
+}
+
+// A SyntaxError is a description of a JSON syntax error.
+type SyntaxError struct {
+	msg    string
+	Offset int64
+}
+
+func (e *SyntaxError) String() string { return e.msg }
+
+// An UnmarshalTypeError describes a JSON value that was not appropriate
+// for a value of a specific Go type.
+type UnmarshalTypeError struct {
+	Value string
+	Type  reflect.Type
+}
+
+func (e *UnmarshalTypeError) String() string {
+	return "json: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
+}
+
+// An InvalidUnmarshalError describes an invalid argument passed to
+// Unmarshal.  (The argument to Unmarshal must be a non-nil pointer.)
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) String() string {
+	if e.Type == nil {
+		return "json: Unmarshal(nil)"
+	}
+	if e.Type.Kind() != reflect.Ptr {
+		return "json: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "json: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+var textUnmarshalerType = reflect.TypeOf(new(TextUnmarshaler)).Elem()
+
+// A Number represents a JSON number literal exactly as it appeared in
+// the input, so that values such as large integers or
+// arbitrary-precision decimals can round-trip without the precision
+// loss that comes from going through float64. It is produced in place
+// of float64 when a Decoder has UseNumber enabled and the destination
+// is an interface{}.
+type Number string
+
+// String returns the literal text of the number.
+func (n Number) String() string { return string(n) }
+
+// Float64 returns the number as a float64.
+func (n Number) Float64() (float64, os.Error) {
+	return strconv.Atof64(string(n))
+}
+
+// Int64 returns the number as an int64.
+func (n Number) Int64() (int64, os.Error) {
+	return strconv.Atoi64(string(n))
+}
+
+// decodeState holds the state while decoding a JSON value.
+type decodeState struct {
+	data      []byte
+	off       int
+	useNumber bool
+}
+
+func (d *decodeState) init(data []byte) *decodeState {
+	d.data = data
+	d.off = 0
+	return d
+}
+
+func (d *decodeState) error(err os.Error) {
+	panic(err)
+}
+
+func (d *decodeState) syntaxError(msg string) os.Error {
+	return &SyntaxError{msg, int64(d.off)}
+}
+
+func (d *decodeState) unmarshal(v interface{}) (err os.Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(os.Error)
+		}
+	}()
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+
+	d.value(rv.Elem())
+	return nil
+}
+
+func (d *decodeState) skipSpace() {
+	for d.off < len(d.data) {
+		switch d.data[d.off] {
+		case ' ', '\t', '\r', '\n':
+			d.off++
+			continue
+		}
+		break
+	}
+}
+
+// indirect walks down v allocating pointers as needed until it reaches a
+// non-pointer.  If it encounters an Unmarshaler, or (unless decoding a
+// JSON null) a TextUnmarshaler, along the way, it stops and returns it.
+// When decoding a JSON null, it stops at the first settable pointer that
+// is not an Unmarshaler and returns that pointer, so that it can be set
+// to nil.
+//
+// If v is itself a non-pointer but addressable (for instance a struct
+// field), indirect first takes its address, so that a value such as a
+// RawMessage field, which implements Unmarshaler with a pointer
+// receiver, is still found.
+func indirect(v reflect.Value, decodingNull bool) (Unmarshaler, TextUnmarshaler, reflect.Value) {
+	v0 := v
+	haveAddr := false
+	if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
+		haveAddr = true
+		v = v.Addr()
+	}
+	for v.Kind() == reflect.Ptr {
+		if decodingNull && v.CanSet() {
+			if _, ok := v.Interface().(Unmarshaler); !ok {
+				break
+			}
+		}
+		if v.IsNil() {
+			if !v.CanSet() {
+				break
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().NumMethod() > 0 {
+			if u, ok := v.Interface().(Unmarshaler); ok {
+				return u, nil, reflect.Value{}
+			}
+			if !decodingNull {
+				if tu, ok := v.Interface().(TextUnmarshaler); ok {
+					return nil, tu, reflect.Value{}
+				}
+			}
+		}
+		if haveAddr {
+			v = v0
+			haveAddr = false
+		} else {
+			v = v.Elem()
+		}
+	}
+	return nil, nil, v
+}
+
+// value decodes the next JSON value from d and stores it in v.
+func (d *decodeState) value(v reflect.Value) {
+	d.skipSpace()
+	if d.off >= len(d.data) {
+		d.error(d.syntaxError("unexpected end of JSON input"))
+	}
+
+	c := d.data[d.off]
+	u, tu, pv := indirect(v, c == 'n')
+	if u != nil {
+		b := d.valueBytes()
+		if err := u.UnmarshalJSON(b); err != nil {
+			d.error(err)
+		}
+		return
+	}
+
+	switch c {
+	case '{':
+		if tu != nil {
+			d.error(&UnmarshalTypeError{"object", v.Type()})
+		}
+		d.object(pv)
+	case '[':
+		if tu != nil {
+			d.error(&UnmarshalTypeError{"array", v.Type()})
+		}
+		d.array(pv)
+	case '"':
+		start := d.off
+		d.skipString()
+		d.literalStore(d.data[start:d.off], pv, tu)
+	default:
+		start := d.off
+		d.skipLiteralOrNumber()
+		d.literalStore(d.data[start:d.off], pv, tu)
+	}
+}
+
+// valueBytes returns the raw bytes of the next JSON value and advances
+// past it, without decoding it into any particular Go value.
+func (d *decodeState) valueBytes() []byte {
+	start := d.off
+	d.skipValue()
+	return d.data[start:d.off]
+}
+
+func (d *decodeState) skipValue() {
+	d.skipSpace()
+	if d.off >= len(d.data) {
+		d.error(d.syntaxError("unexpected end of JSON input"))
+	}
+	switch d.data[d.off] {
+	case '{':
+		d.skipObject()
+	case '[':
+		d.skipArray()
+	case '"':
+		d.skipString()
+	default:
+		d.skipLiteralOrNumber()
+	}
+}
+
+func (d *decodeState) skipLiteralOrNumber() {
+	switch d.data[d.off] {
+	case 't':
+		d.skipLiteral("true")
+	case 'f':
+		d.skipLiteral("false")
+	case 'n':
+		d.skipLiteral("null")
+	default:
+		d.skipNumber()
+	}
+}
+
+func (d *decodeState) skipLiteral(lit string) {
+	if d.off+len(lit) > len(d.data) || string(d.data[d.off:d.off+len(lit)]) != lit {
+		d.error(d.syntaxError("invalid character looking for beginning of value"))
+	}
+	d.off += len(lit)
+}
+
+func (d *decodeState) skipNumber() {
+	start := d.off
+	if d.off < len(d.data) && d.data[d.off] == '-' {
+		d.off++
+	}
+	if d.off >= len(d.data) || d.data[d.off] < '0' || d.data[d.off] > '9' {
+		d.error(d.syntaxError("invalid number literal"))
+	}
+	for d.off < len(d.data) && '0' <= d.data[d.off] && d.data[d.off] <= '9' {
+		d.off++
+	}
+	if d.off < len(d.data) && d.data[d.off] == '.' {
+		d.off++
+		for d.off < len(d.data) && '0' <= d.data[d.off] && d.data[d.off] <= '9' {
+			d.off++
+		}
+	}
+	if d.off < len(d.data) && (d.data[d.off] == 'e' || d.data[d.off] == 'E') {
+		d.off++
+		if d.off < len(d.data) && (d.data[d.off] == '+' || d.data[d.off] == '-') {
+			d.off++
+		}
+		for d.off < len(d.data) && '0' <= d.data[d.off] && d.data[d.off] <= '9' {
+			d.off++
+		}
+	}
+	if d.off == start {
+		d.error(d.syntaxError("invalid number literal"))
+	}
+}
+
+func (d *decodeState) skipString() {
+	if d.off >= len(d.data) || d.data[d.off] != '"' {
+		d.error(d.syntaxError("expected string"))
+	}
+	d.off++
+	for {
+		if d.off >= len(d.data) {
+			d.error(d.syntaxError("unexpected end of JSON input"))
+		}
+		switch c := d.data[d.off]; c {
+		case '"':
+			d.off++
+			return
+		case '\\':
+			d.off += 2
+		default:
+			d.off++
+		}
+	}
+}
+
+func (d *decodeState) skipObject() {
+	d.off++ // '{'
+	d.skipSpace()
+	if d.off < len(d.data) && d.data[d.off] == '}' {
+		d.off++
+		return
+	}
+	for {
+		d.skipSpace()
+		d.skipString()
+		d.skipSpace()
+		if d.off >= len(d.data) || d.data[d.off] != ':' {
+			d.error(d.syntaxError("expected ':' after object key"))
+		}
+		d.off++
+		d.skipValue()
+		d.skipSpace()
+		if d.off >= len(d.data) {
+			d.error(d.syntaxError("unexpected end of JSON input"))
+		}
+		switch d.data[d.off] {
+		case ',':
+			d.off++
+		case '}':
+			d.off++
+			return
+		default:
+			d.error(d.syntaxError("invalid character after object key:value pair"))
+		}
+	}
+}
+
+func (d *decodeState) skipArray() {
+	d.off++ // '['
+	d.skipSpace()
+	if d.off < len(d.data) && d.data[d.off] == ']' {
+		d.off++
+		return
+	}
+	for {
+		d.skipValue()
+		d.skipSpace()
+		if d.off >= len(d.data) {
+			d.error(d.syntaxError("unexpected end of JSON input"))
+		}
+		switch d.data[d.off] {
+		case ',':
+			d.off++
+			d.skipSpace()
+		case ']':
+			d.off++
+			return
+		default:
+			d.error(d.syntaxError("invalid character after array element"))
+		}
+	}
+}
+
+// object decodes a JSON object, whose opening brace has not yet been
+// consumed, into v, which must be a map, a struct, or an empty
+// interface.
+func (d *decodeState) object(v reflect.Value) {
+	if d.data[d.off] != '{' {
+		d.error(d.syntaxError("expected '{'"))
+	}
+	d.off++
+
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		m := make(map[string]interface{})
+		d.objectBody(reflect.ValueOf(m))
+		v.Set(reflect.ValueOf(m))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		t := v.Type()
+		if t.Key().Kind() != reflect.String && !reflect.PtrTo(t.Key()).Implements(textUnmarshalerType) {
+			d.error(&UnmarshalTypeError{"object", t})
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(t))
+		}
+		d.objectBody(v)
+	case reflect.Struct:
+		d.objectBody(v)
+	default:
+		d.error(&UnmarshalTypeError{"object", v.Type()})
+	}
+}
+
+// objectBody decodes the key:value pairs of a JSON object (with the
+// opening brace already consumed) into the map or struct v.
+func (d *decodeState) objectBody(v reflect.Value) {
+	d.skipSpace()
+	if d.off < len(d.data) && d.data[d.off] == '}' {
+		d.off++
+		return
+	}
+	for {
+		d.skipSpace()
+		if d.off >= len(d.data) || d.data[d.off] != '"' {
+			d.error(d.syntaxError("expected string key"))
+		}
+		start := d.off
+		d.skipString()
+		key, ok := unquote(d.data[start:d.off])
+		if !ok {
+			d.error(d.syntaxError("invalid object key string"))
+		}
+
+		d.skipSpace()
+		if d.off >= len(d.data) || d.data[d.off] != ':' {
+			d.error(d.syntaxError("expected ':' after object key"))
+		}
+		d.off++
+
+		switch v.Kind() {
+		case reflect.Map:
+			elem := reflect.New(v.Type().Elem()).Elem()
+			d.value(elem)
+			kt := v.Type().Key()
+			var kv reflect.Value
+			if reflect.PtrTo(kt).Implements(textUnmarshalerType) {
+				kp := reflect.New(kt)
+				if err := kp.Interface().(TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+					d.error(err)
+				}
+				kv = kp.Elem()
+			} else {
+				kv = reflect.ValueOf(key).Convert(kt)
+			}
+			v.SetMapIndex(kv, elem)
+		case reflect.Struct:
+			if i, ok := fieldIndex(v.Type(), key); ok {
+				d.value(v.Field(i))
+			} else {
+				d.skipValue()
+			}
+		}
+
+		d.skipSpace()
+		if d.off >= len(d.data) {
+			d.error(d.syntaxError("unexpected end of JSON input"))
+		}
+		switch d.data[d.off] {
+		case ',':
+			d.off++
+		case '}':
+			d.off++
+			return
+		default:
+			d.error(d.syntaxError("invalid character after object key:value pair"))
+		}
+	}
+}
+
+// fieldIndex returns the index of the struct field of t whose name or
+// json tag matches key, using the same tag syntax as Marshal. An exact
+// match wins; failing that, fieldIndex falls back to a case-insensitive
+// match, so that e.g. a field tagged json:"id" also accepts "ID".
+func fieldIndex(t reflect.Type, key string) (int, bool) {
+	fold := -1
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Name
+		if tv := f.Tag.Get("json"); tv != "" {
+			name, _ := parseTag(tv)
+			if isValidTag(name) {
+				tag = name
+			}
+		}
+		if tag == key {
+			return i, true
+		}
+		if fold < 0 && strings.EqualFold(tag, key) {
+			fold = i
+		}
+	}
+	if fold >= 0 {
+		return fold, true
+	}
+	return 0, false
+}
+
+// array decodes a JSON array, whose opening bracket has not yet been
+// consumed, into v, which must be a slice, an array, or an empty
+// interface.
+func (d *decodeState) array(v reflect.Value) {
+	if d.data[d.off] != '[' {
+		d.error(d.syntaxError("expected '['"))
+	}
+	d.off++
+
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		var a []interface{}
+		d.skipSpace()
+		if d.off < len(d.data) && d.data[d.off] == ']' {
+			d.off++
+			v.Set(reflect.ValueOf(a))
+			return
+		}
+		for {
+			var e interface{}
+			d.value(reflect.ValueOf(&e).Elem())
+			a = append(a, e)
+			d.skipSpace()
+			if d.off >= len(d.data) {
+				d.error(d.syntaxError("unexpected end of JSON input"))
+			}
+			if d.data[d.off] == ',' {
+				d.off++
+				continue
+			}
+			if d.data[d.off] == ']' {
+				d.off++
+				break
+			}
+			d.error(d.syntaxError("invalid character after array element"))
+		}
+		v.Set(reflect.ValueOf(a))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		// handled below
+	default:
+		d.error(&UnmarshalTypeError{"array", v.Type()})
+	}
+
+	i := 0
+	d.skipSpace()
+	if d.off < len(d.data) && d.data[d.off] == ']' {
+		d.off++
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+		}
+		return
+	}
+	for {
+		if v.Kind() == reflect.Slice {
+			if i >= v.Cap() {
+				newcap := v.Cap() + v.Cap()/2
+				if newcap < 4 {
+					newcap = 4
+				}
+				newv := reflect.MakeSlice(v.Type(), v.Len(), newcap)
+				reflect.Copy(newv, v)
+				v.Set(newv)
+			}
+			if i >= v.Len() {
+				v.SetLen(i + 1)
+			}
+		}
+		if i < v.Len() {
+			d.value(v.Index(i))
+		} else {
+			d.skipValue()
+		}
+		i++
+		d.skipSpace()
+		if d.off >= len(d.data) {
+			d.error(d.syntaxError("unexpected end of JSON input"))
+		}
+		if d.data[d.off] == ',' {
+			d.off++
+			d.skipSpace()
+			continue
+		}
+		if d.data[d.off] == ']' {
+			d.off++
+			break
+		}
+		d.error(d.syntaxError("invalid character after array element"))
+	}
+	if i < v.Len() {
+		if v.Kind() == reflect.Array {
+			z := reflect.Zero(v.Type().Elem())
+			for ; i < v.Len(); i++ {
+				v.Index(i).Set(z)
+			}
+		} else {
+			v.SetLen(i)
+		}
+	}
+	if i == 0 && v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+}
+
+// literalStore decodes the literal (string, number, bool, or null) item
+// into v, or, if tu is non-nil, calls tu.UnmarshalText with its unquoted
+// contents.
+func (d *decodeState) literalStore(item []byte, v reflect.Value, tu TextUnmarshaler) {
+	if len(item) == 0 {
+		d.error(d.syntaxError("unexpected end of JSON input"))
+	}
+	isNull := item[0] == 'n'
+	if tu != nil {
+		if isNull {
+			return
+		}
+		s, ok := unquote(item)
+		if !ok {
+			d.error(d.syntaxError("JSON string required to satisfy TextUnmarshaler"))
+		}
+		if err := tu.UnmarshalText([]byte(s)); err != nil {
+			d.error(err)
+		}
+		return
+	}
+
+	if !v.IsValid() {
+		return
+	}
+
+	switch c := item[0]; c {
+	case 'n': // null
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+	case 't', 'f': // true, false
+		value := c == 't'
+		switch v.Kind() {
+		default:
+			d.error(&UnmarshalTypeError{"bool", v.Type()})
+		case reflect.Bool:
+			v.SetBool(value)
+		case reflect.Interface:
+			v.Set(reflect.ValueOf(value))
+		}
+	case '"': // string
+		s, ok := unquote(item)
+		if !ok {
+			d.error(d.syntaxError("invalid JSON string literal"))
+		}
+		switch v.Kind() {
+		default:
+			d.error(&UnmarshalTypeError{"string", v.Type()})
+		case reflect.Slice:
+			if v.Type() != byteSliceType {
+				d.error(&UnmarshalTypeError{"string", v.Type()})
+			}
+			b := make([]byte, base64.StdEncoding.DecodedLen(len(s)))
+			n, err := base64.StdEncoding.Decode(b, []byte(s))
+			if err != nil {
+				d.error(err)
+			}
+			v.Set(reflect.ValueOf(b[:n]))
+		case reflect.String:
+			v.SetString(s)
+		case reflect.Interface:
+			v.Set(reflect.ValueOf(s))
+		}
+	default: // number
+		if c != '-' && (c < '0' || c > '9') {
+			d.error(d.syntaxError("invalid character '" + string(c) + "' looking for beginning of value"))
+		}
+		s := string(item)
+		switch v.Kind() {
+		default:
+			d.error(&UnmarshalTypeError{"number", v.Type()})
+		case reflect.Interface:
+			if d.useNumber {
+				v.Set(reflect.ValueOf(Number(s)))
+				break
+			}
+			n, err := strconv.Atof64(s)
+			if err != nil {
+				d.error(&UnmarshalTypeError{"number " + s, v.Type()})
+			}
+			v.Set(reflect.ValueOf(n))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.Atoi64(s)
+			if err != nil {
+				d.error(&UnmarshalTypeError{"number " + s, v.Type()})
+			}
+			v.SetInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n, err := strconv.Atoui64(s)
+			if err != nil {
+				d.error(&UnmarshalTypeError{"number " + s, v.Type()})
+			}
+			v.SetUint(n)
+		case reflect.Float32, reflect.Float64:
+			n, err := strconv.Atof64(s)
+			if err != nil {
+				d.error(&UnmarshalTypeError{"number " + s, v.Type()})
+			}
+			v.SetFloat(n)
+		}
+	}
+}
+
+// unquote interprets item as a double-quoted JSON string literal,
+// unescaping it, and reports whether item was a well-formed string.
+func unquote(item []byte) (string, bool) {
+	if len(item) < 2 || item[0] != '"' || item[len(item)-1] != '"' {
+		return "", false
+	}
+	s := item[1 : len(item)-1]
+	var buf bytes.Buffer
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c != '\\' {
+			buf.WriteByte(c)
+			i++
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", false
+		}
+		switch s[i] {
+		case '"':
+			buf.WriteByte('"')
+		case '\\':
+			buf.WriteByte('\\')
+		case '/':
+			buf.WriteByte('/')
+		case 'b':
+			buf.WriteByte('\b')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 't':
+			buf.WriteByte('\t')
+		case 'u':
+			if i+4 >= len(s) {
+				return "", false
+			}
+			r, err := strconv.Btoui64(string(s[i+1:i+5]), 16)
+			if err != nil {
+				return "", false
+			}
+			buf.WriteRune(int(r))
+			i += 4
+		default:
+			return "", false
+		}
+		i++
+	}
+	return buf.String(), true
+}
@@ -0,0 +1,142 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package json
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextUnmarshalerStructField(t *testing.T) {
+	type T struct {
+		Name upperString
+	}
+	var v T
+	if err := Unmarshal([]byte(`{"Name":"GOPHER"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if want := upperString("gopher"); v.Name != want {
+		t.Fatalf("Name = %q, want %q", v.Name, want)
+	}
+}
+
+func TestTextUnmarshalerMapKey(t *testing.T) {
+	var m map[upperString]int
+	if err := Unmarshal([]byte(`{"A":1,"B":2}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["a"] != 1 || m["b"] != 2 {
+		t.Fatalf("Unmarshal = %v, want map[a:1 b:2]", m)
+	}
+}
+
+func TestTextUnmarshalerWrongTokenKind(t *testing.T) {
+	type T struct {
+		Name upperString
+	}
+
+	// An object or array can never satisfy TextUnmarshaler: Unmarshal
+	// must reject it with an UnmarshalTypeError rather than silently
+	// leaving Name untouched.
+	for _, data := range []string{`{"Name":{"x":1}}`, `{"Name":[1,2]}`} {
+		var v T
+		err := Unmarshal([]byte(data), &v)
+		if err == nil {
+			t.Errorf("Unmarshal(%s): got nil error, want one", data)
+			continue
+		}
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal(%s): got %T, want *UnmarshalTypeError", data, err)
+		}
+		if v.Name != "" {
+			t.Errorf("Unmarshal(%s): Name = %q, want unchanged", data, v.Name)
+		}
+	}
+
+	// A bare number or bool isn't a quoted string either; literalStore
+	// reports that with a SyntaxError rather than silently succeeding.
+	for _, data := range []string{`{"Name":42}`, `{"Name":true}`} {
+		var v T
+		if err := Unmarshal([]byte(data), &v); err == nil {
+			t.Errorf("Unmarshal(%s): got nil error, want one", data)
+		}
+	}
+}
+
+func TestTextUnmarshalerNull(t *testing.T) {
+	type T struct {
+		Name upperString
+	}
+	v := T{Name: "gopher"}
+	if err := Unmarshal([]byte(`{"Name":null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "gopher" {
+		t.Fatalf("Name = %q, want unchanged gopher", v.Name)
+	}
+}
+
+func TestFieldIndexCaseInsensitive(t *testing.T) {
+	type T struct {
+		ID   int `json:"id"`
+		Name string
+	}
+	var v T
+	if err := Unmarshal([]byte(`{"ID":7,"NAME":"gopher"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != 7 || v.Name != "gopher" {
+		t.Fatalf("Unmarshal = %+v, want {ID:7 Name:gopher}", v)
+	}
+}
+
+func TestNumberDecode(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(`[1, 2.5, 123456789012345678]`))
+	dec.UseNumber()
+	var v []interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1", "2.5", "123456789012345678"}
+	for i, n := range v {
+		num, ok := n.(Number)
+		if !ok {
+			t.Fatalf("v[%d] = %T, want Number", i, n)
+		}
+		if num.String() != want[i] {
+			t.Errorf("v[%d] = %s, want %s", i, num, want[i])
+		}
+	}
+	if i, err := v[2].(Number).Int64(); err != nil || i != 123456789012345678 {
+		t.Errorf("v[2].Int64() = %d, %v, want 123456789012345678, nil", i, err)
+	}
+}
+
+func TestNumberRoundTrip(t *testing.T) {
+	b, err := Marshal(Number("3.14159"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "3.14159" {
+		t.Fatalf("Marshal = %s, want 3.14159", b)
+	}
+	if _, err := Marshal(Number("not a number")); err == nil {
+		t.Fatal("Marshal(invalid Number): got nil error, want one")
+	}
+}
+
+func TestFieldIndexExactMatchPreferred(t *testing.T) {
+	type T struct {
+		Id int
+		ID int
+	}
+	var v T
+	if err := Unmarshal([]byte(`{"ID":1}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != 1 || v.Id != 0 {
+		t.Fatalf("Unmarshal = %+v, want {Id:0 ID:1}", v)
+	}
+}
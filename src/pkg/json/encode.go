@@ -11,11 +11,9 @@ package json
 
 import (
 	"bytes"
-	"encoding/base64"
 	"os"
 	"reflect"
 	"runtime"
-	"sort"
 	"strconv"
 	"unicode"
 	"utf8"
@@ -31,7 +29,9 @@ import (
 //
 // Boolean values encode as JSON booleans.
 //
-// Floating point and integer values encode as JSON numbers.
+// Floating point and integer values encode as JSON numbers. A Number
+// encodes as the literal text it holds, so long as that text is a
+// valid JSON number.
 //
 // String values encode as JSON strings, with each invalid UTF-8 sequence
 // replaced by the encoding of the Unicode replacement character U+FFFD.
@@ -70,9 +70,10 @@ import (
 // The key name will be used if it's a non-empty string consisting of
 // only Unicode letters, digits, dollar signs, hyphens, and underscores.
 //
-// Map values encode as JSON objects.
-// The map's key type must be string; the object keys are used directly
-// as map keys.
+// Map values encode as JSON objects. The map's key type must either be
+// string or implement TextMarshaler; the resulting object keys are
+// sorted lexicographically, after MarshalText encoding for
+// TextMarshaler keys.
 //
 // Pointer values encode as the value pointed to.
 // A nil pointer encodes as the null JSON object.
@@ -154,6 +155,17 @@ type Marshaler interface {
 	MarshalJSON() ([]byte, os.Error)
 }
 
+// TextMarshaler is the interface implemented by objects that can marshal
+// themselves into a textual form.  MarshalText encodes the receiver into
+// UTF-8 text and returns the result.  Values implementing TextMarshaler
+// are encoded as JSON strings, which lets types such as net.IP or
+// time.Time be marshaled without a MarshalJSON method of their own; if
+// the value is also used as a map key, the encoded text is used as the
+// object key, sorted lexicographically along with the other keys.
+type TextMarshaler interface {
+	MarshalText() ([]byte, os.Error)
+}
+
 type UnsupportedTypeError struct {
 	Type reflect.Type
 }
@@ -170,6 +182,18 @@ func (e *InvalidUTF8Error) String() string {
 	return "json: invalid UTF-8 in string: " + strconv.Quote(e.S)
 }
 
+// An UnsupportedValueError is returned by Marshal when attempting to
+// encode an unsupported value, such as a NaN or infinite float, which
+// have no valid representation in the JSON number grammar (RFC 4627).
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+func (e *UnsupportedValueError) String() string {
+	return "json: unsupported value: " + e.Str
+}
+
 type MarshalerError struct {
 	Type  reflect.Type
 	Error os.Error
@@ -186,6 +210,25 @@ type interfaceOrPtrValue interface {
 
 var hex = "0123456789abcdef"
 
+var textMarshalerType = reflect.TypeOf(new(TextMarshaler)).Elem()
+
+// textMarshaler returns v's TextMarshaler, checking both v itself and,
+// if v is addressable, *v.
+func textMarshaler(v reflect.Value) (TextMarshaler, bool) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, false
+	}
+	if m, ok := v.Interface().(TextMarshaler); ok {
+		return m, true
+	}
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		if m, ok := v.Addr().Interface().(TextMarshaler); ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
 // An encodeState encodes JSON into a bytes.Buffer.
 type encodeState struct {
 	bytes.Buffer // accumulated output
@@ -210,6 +253,54 @@ func (e *encodeState) error(err os.Error) {
 
 var byteSliceType = reflect.TypeOf([]byte(nil))
 
+var numberType = reflect.TypeOf(Number(""))
+
+// isValidNumber reports whether s is a valid JSON number literal.
+func isValidNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	i := 0
+	if s[i] == '-' {
+		i++
+	}
+	if i >= len(s) {
+		return false
+	}
+	if s[i] == '0' {
+		i++
+	} else {
+		if s[i] < '1' || s[i] > '9' {
+			return false
+		}
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if i >= len(s) || s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if i >= len(s) || s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+	}
+	return i == len(s)
+}
+
 func isEmptyValue(v reflect.Value) bool {
 	switch v.Kind() {
 	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
@@ -232,155 +323,11 @@ func (e *encodeState) reflectValue(v reflect.Value) {
 	e.reflectValueQuoted(v, false)
 }
 
-// reflectValueQuoted writes the value in v to the output.
+// reflectValueQuoted writes the value in v to the output, dispatching
+// to the cached encoderFunc for v's type (see encoder.go).
 // If quoted is true, the serialization is wrapped in a JSON string.
 func (e *encodeState) reflectValueQuoted(v reflect.Value, quoted bool) {
-	if !v.IsValid() {
-		e.WriteString("null")
-		return
-	}
-
-	if j, ok := v.Interface().(Marshaler); ok {
-		b, err := j.MarshalJSON()
-		if err == nil {
-			// copy JSON into buffer, checking validity.
-			err = Compact(&e.Buffer, b)
-		}
-		if err != nil {
-			e.error(&MarshalerError{v.Type(), err})
-		}
-		return
-	}
-
-	writeString := (*encodeState).WriteString
-	if quoted {
-		writeString = (*encodeState).string
-	}
-
-	switch v.Kind() {
-	case reflect.Bool:
-		x := v.Bool()
-		if x {
-			writeString(e, "true")
-		} else {
-			writeString(e, "false")
-		}
-
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		writeString(e, strconv.Itoa64(v.Int()))
-
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		writeString(e, strconv.Uitoa64(v.Uint()))
-
-	case reflect.Float32, reflect.Float64:
-		writeString(e, strconv.FtoaN(v.Float(), 'g', -1, v.Type().Bits()))
-
-	case reflect.String:
-		if quoted {
-			sb, err := Marshal(v.String())
-			if err != nil {
-				e.error(err)
-			}
-			e.string(string(sb))
-		} else {
-			e.string(v.String())
-		}
-
-	case reflect.Struct:
-		e.WriteByte('{')
-		t := v.Type()
-		n := v.NumField()
-		first := true
-		for i := 0; i < n; i++ {
-			f := t.Field(i)
-			if f.PkgPath != "" {
-				continue
-			}
-			tag, omitEmpty, quoted := f.Name, false, false
-			if tv := f.Tag.Get("json"); tv != "" {
-				name, opts := parseTag(tv)
-				if isValidTag(name) {
-					tag = name
-				}
-				omitEmpty = opts.Contains("omitempty")
-				quoted = opts.Contains("string")
-			}
-			fieldValue := v.Field(i)
-			if omitEmpty && isEmptyValue(fieldValue) {
-				continue
-			}
-			if first {
-				first = false
-			} else {
-				e.WriteByte(',')
-			}
-			e.string(tag)
-			e.WriteByte(':')
-			e.reflectValueQuoted(fieldValue, quoted)
-		}
-		e.WriteByte('}')
-
-	case reflect.Map:
-		if v.Type().Key().Kind() != reflect.String {
-			e.error(&UnsupportedTypeError{v.Type()})
-		}
-		if v.IsNil() {
-			e.WriteString("null")
-			break
-		}
-		e.WriteByte('{')
-		var sv stringValues = v.MapKeys()
-		sort.Sort(sv)
-		for i, k := range sv {
-			if i > 0 {
-				e.WriteByte(',')
-			}
-			e.string(k.String())
-			e.WriteByte(':')
-			e.reflectValue(v.MapIndex(k))
-		}
-		e.WriteByte('}')
-
-	case reflect.Array, reflect.Slice:
-		if v.Type() == byteSliceType {
-			e.WriteByte('"')
-			s := v.Interface().([]byte)
-			if len(s) < 1024 {
-				// for small buffers, using Encode directly is much faster.
-				dst := make([]byte, base64.StdEncoding.EncodedLen(len(s)))
-				base64.StdEncoding.Encode(dst, s)
-				e.Write(dst)
-			} else {
-				// for large buffers, avoid unnecessary extra temporary
-				// buffer space.
-				enc := base64.NewEncoder(base64.StdEncoding, e)
-				enc.Write(s)
-				enc.Close()
-			}
-			e.WriteByte('"')
-			break
-		}
-		e.WriteByte('[')
-		n := v.Len()
-		for i := 0; i < n; i++ {
-			if i > 0 {
-				e.WriteByte(',')
-			}
-			e.reflectValue(v.Index(i))
-		}
-		e.WriteByte(']')
-
-	case reflect.Interface, reflect.Ptr:
-		if v.IsNil() {
-			e.WriteString("null")
-			return
-		}
-		e.reflectValue(v.Elem())
-
-	default:
-		e.error(&UnsupportedTypeError{v.Type()})
-	}
-	return
+	valueEncoder(v)(e, v, quoted)
 }
 
 func isValidTag(s string) bool {
@@ -395,14 +342,18 @@ func isValidTag(s string) bool {
 	return true
 }
 
-// stringValues is a slice of reflect.Value holding *reflect.StringValue.
-// It implements the methods to sort by string.
-type stringValues []reflect.Value
+// mapKeyValue pairs a map's already-encoded key string with its value,
+// so that map entries can be sorted by key before being written out.
+type mapKeyValue struct {
+	key   string
+	value reflect.Value
+}
+
+type mapKeyValues []mapKeyValue
 
-func (sv stringValues) Len() int           { return len(sv) }
-func (sv stringValues) Swap(i, j int)      { sv[i], sv[j] = sv[j], sv[i] }
-func (sv stringValues) Less(i, j int) bool { return sv.get(i) < sv.get(j) }
-func (sv stringValues) get(i int) string   { return sv[i].String() }
+func (sv mapKeyValues) Len() int           { return len(sv) }
+func (sv mapKeyValues) Swap(i, j int)      { sv[i], sv[j] = sv[j], sv[i] }
+func (sv mapKeyValues) Less(i, j int) bool { return sv[i].key < sv[j].key }
 
 func (e *encodeState) string(s string) (int, os.Error) {
 	len0 := e.Len()
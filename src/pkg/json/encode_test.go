@@ -0,0 +1,187 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package json
+
+import (
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// upperString round-trips through JSON as its upper-cased text form, to
+// exercise the TextMarshaler/TextUnmarshaler fallback: MarshalText has a
+// value receiver so upperString also qualifies as a map key, the one
+// place a pointer-receiver implementation could never be reached.
+type upperString string
+
+func (u upperString) MarshalText() ([]byte, os.Error) {
+	return []byte(strings.ToUpper(string(u))), nil
+}
+
+func (u *upperString) UnmarshalText(text []byte) os.Error {
+	*u = upperString(strings.ToLower(string(text)))
+	return nil
+}
+
+func TestTextMarshalerStructField(t *testing.T) {
+	type T struct {
+		Name upperString
+	}
+	b, err := Marshal(T{Name: "gopher"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Name":"GOPHER"}`; string(b) != want {
+		t.Fatalf("Marshal = %s, want %s", b, want)
+	}
+}
+
+func TestTextMarshalerMapKey(t *testing.T) {
+	m := map[upperString]int{"b": 2, "a": 1}
+	b, err := Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"A":1,"B":2}`; string(b) != want {
+		t.Fatalf("Marshal = %s, want %s", b, want)
+	}
+}
+
+func TestUnsupportedFloatValue(t *testing.T) {
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		_, err := Marshal(f)
+		if err == nil {
+			t.Errorf("Marshal(%v): got nil error, want UnsupportedValueError", f)
+			continue
+		}
+		if _, ok := err.(*UnsupportedValueError); !ok {
+			t.Errorf("Marshal(%v): got %T, want *UnsupportedValueError", f, err)
+		}
+	}
+}
+
+func TestFiniteFloatStillEncodes(t *testing.T) {
+	b, err := Marshal(1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "1.5" {
+		t.Fatalf("Marshal(1.5) = %s, want 1.5", b)
+	}
+}
+
+// selfRefNode holds a pointer to its own type, so building its
+// typeEncoder recursively asks typeEncoder to build an encoder for
+// *selfRefNode while selfRefNode's own encoder is still under
+// construction. This must not deadlock.
+type selfRefNode struct {
+	Val  int
+	Next *selfRefNode
+}
+
+func TestEncodeSelfReferentialType(t *testing.T) {
+	v := &selfRefNode{Val: 1, Next: &selfRefNode{Val: 2, Next: &selfRefNode{Val: 3}}}
+
+	done := make(chan []byte, 1)
+	go func() {
+		b, err := Marshal(v)
+		if err != nil {
+			t.Error(err)
+			done <- nil
+			return
+		}
+		done <- b
+	}()
+
+	select {
+	case b := <-done:
+		want := `{"Val":1,"Next":{"Val":2,"Next":{"Val":3,"Next":null}}}`
+		if string(b) != want {
+			t.Errorf("Marshal = %s, want %s", b, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Marshal deadlocked on a self-referential type")
+	}
+}
+
+// TestEncodeConcurrentFirstUse is a regression test for the
+// typeEncoder cache's WaitGroup-guarded placeholder: the first Marshal
+// calls for a given type, racing from many goroutines, must all block
+// until the real encoder is built rather than panicking or deadlocking.
+func TestEncodeConcurrentFirstUse(t *testing.T) {
+	type concurrentType struct {
+		A int
+		B string
+	}
+
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			b, err := Marshal(concurrentType{A: i, B: "x"})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if len(b) == 0 {
+				errs <- os.NewError("empty output")
+			}
+		}(i)
+	}
+
+	done := make(chan bool, 1)
+	go func() {
+		wg.Wait()
+		done <- true
+	}()
+
+	select {
+	case <-done:
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("concurrent first Marshal calls deadlocked")
+	}
+}
+
+type benchPoint struct {
+	X, Y int
+}
+
+type benchRecord struct {
+	Name   string `json:"name"`
+	Points []benchPoint
+	Active bool `json:"active,omitempty"`
+}
+
+// BenchmarkEncodeStructSlice exercises the per-type encoder cache added
+// in typeEncoder: repeated Marshal calls on the same struct shape
+// should build the reflect-based dispatch plan once and then just walk
+// the cached encoderFuncs.
+func BenchmarkEncodeStructSlice(b *testing.B) {
+	records := make([]benchRecord, 100)
+	for i := range records {
+		records[i] = benchRecord{
+			Name:   "record",
+			Points: []benchPoint{{1, 2}, {3, 4}},
+			Active: i%2 == 0,
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(records); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package json
+
+import (
+	"encoding/base64"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// encoderFunc writes the JSON encoding of v to e. If quoted is true,
+// the encoding of a primitive value is itself wrapped in a JSON
+// string, as used for struct fields tagged with the "string" option.
+type encoderFunc func(e *encodeState, v reflect.Value, quoted bool)
+
+var (
+	encoderCacheLock sync.RWMutex
+	encoderCache     = make(map[reflect.Type]encoderFunc)
+)
+
+// valueEncoder returns the encoderFunc for v, or invalidValueEncoder if
+// v is the zero Value.
+func valueEncoder(v reflect.Value) encoderFunc {
+	if !v.IsValid() {
+		return invalidValueEncoder
+	}
+	return typeEncoder(v.Type())
+}
+
+// typeEncoder returns the cached encoderFunc for t, building and
+// caching one if this is the first time t has been seen.
+func typeEncoder(t reflect.Type) encoderFunc {
+	encoderCacheLock.RLock()
+	f := encoderCache[t]
+	encoderCacheLock.RUnlock()
+	if f != nil {
+		return f
+	}
+
+	// To deal with recursive types, populate the cache with an
+	// indirect func before building the real one, so that a type that
+	// refers to itself (e.g. a linked list node) can still look itself
+	// up while being built, instead of recursing forever.
+	encoderCacheLock.Lock()
+	if f = encoderCache[t]; f == nil {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		encoderCache[t] = func(e *encodeState, v reflect.Value, quoted bool) {
+			wg.Wait()
+			f(e, v, quoted)
+		}
+		encoderCacheLock.Unlock()
+
+		f = newTypeEncoder(t)
+		wg.Done()
+
+		encoderCacheLock.Lock()
+		encoderCache[t] = f
+	}
+	encoderCacheLock.Unlock()
+	return f
+}
+
+var marshalerType = reflect.TypeOf(new(Marshaler)).Elem()
+
+// newTypeEncoder builds an encoderFunc for t, resolving the tag parsing,
+// field layout, and interface-implementation checks that used to happen
+// on every call to reflectValueQuoted into a one-time dispatch.
+func newTypeEncoder(t reflect.Type) encoderFunc {
+	if t.Implements(marshalerType) {
+		return marshalerEncoder
+	}
+	if t.Implements(textMarshalerType) {
+		return textMarshalerEncoder
+	}
+	// Only *T, not T, implements TextMarshaler: fall back to the
+	// ordinary encoding of T when v isn't addressable (e.g. a map
+	// value), the same two-way check textMarshaler (encode.go) made
+	// per-call before this cache existed.
+	if t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(textMarshalerType) {
+		return newCondAddrEncoder(addrTextMarshalerEncoder, newKindEncoder(t))
+	}
+	return newKindEncoder(t)
+}
+
+// newCondAddrEncoder returns an encoderFunc that uses canAddrEnc when v
+// is addressable and elseEnc otherwise, for the case where only *T (not
+// T) implements TextMarshaler.
+func newCondAddrEncoder(canAddrEnc, elseEnc encoderFunc) encoderFunc {
+	return func(e *encodeState, v reflect.Value, quoted bool) {
+		if v.CanAddr() {
+			canAddrEnc(e, v, quoted)
+		} else {
+			elseEnc(e, v, quoted)
+		}
+	}
+}
+
+func newKindEncoder(t reflect.Type) encoderFunc {
+	if t == numberType {
+		return numberEncoder
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolEncoder
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intEncoder
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintEncoder
+	case reflect.Float32:
+		return floatEncoder(32)
+	case reflect.Float64:
+		return floatEncoder(64)
+	case reflect.String:
+		return stringEncoder
+	case reflect.Interface:
+		return interfaceEncoder
+	case reflect.Struct:
+		return newStructEncoder(t)
+	case reflect.Map:
+		return newMapEncoder(t)
+	case reflect.Slice:
+		return newSliceEncoder(t)
+	case reflect.Array:
+		return newArrayEncoder(t)
+	case reflect.Ptr:
+		return newPtrEncoder(t)
+	default:
+		return unsupportedTypeEncoder
+	}
+}
+
+func invalidValueEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	e.WriteString("null")
+}
+
+func unsupportedTypeEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	e.error(&UnsupportedTypeError{v.Type()})
+}
+
+func marshalerEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	m := v.Interface().(Marshaler)
+	b, err := m.MarshalJSON()
+	if err == nil {
+		err = Compact(&e.Buffer, b)
+	}
+	if err != nil {
+		e.error(&MarshalerError{v.Type(), err})
+	}
+}
+
+func textMarshalerEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		e.WriteString("null")
+		return
+	}
+	m := v.Interface().(TextMarshaler)
+	b, err := m.MarshalText()
+	if err != nil {
+		e.error(&MarshalerError{v.Type(), err})
+	}
+	writeTextMarshal(e, b, quoted)
+}
+
+func addrTextMarshalerEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	m := v.Addr().Interface().(TextMarshaler)
+	b, err := m.MarshalText()
+	if err != nil {
+		e.error(&MarshalerError{v.Type(), err})
+	}
+	writeTextMarshal(e, b, quoted)
+}
+
+func writeTextMarshal(e *encodeState, b []byte, quoted bool) {
+	if quoted {
+		sb, err := Marshal(string(b))
+		if err != nil {
+			e.error(err)
+		}
+		e.string(string(sb))
+	} else {
+		e.string(string(b))
+	}
+}
+
+func boolEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	s := "false"
+	if v.Bool() {
+		s = "true"
+	}
+	if quoted {
+		e.string(s)
+	} else {
+		e.WriteString(s)
+	}
+}
+
+func intEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	s := strconv.Itoa64(v.Int())
+	if quoted {
+		e.string(s)
+	} else {
+		e.WriteString(s)
+	}
+}
+
+func uintEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	s := strconv.Uitoa64(v.Uint())
+	if quoted {
+		e.string(s)
+	} else {
+		e.WriteString(s)
+	}
+}
+
+func floatEncoder(bits int) encoderFunc {
+	return func(e *encodeState, v reflect.Value, quoted bool) {
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			e.error(&UnsupportedValueError{v, strconv.FtoaN(f, 'g', -1, bits)})
+		}
+		s := strconv.FtoaN(f, 'g', -1, bits)
+		if quoted {
+			e.string(s)
+		} else {
+			e.WriteString(s)
+		}
+	}
+}
+
+func numberEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	s := v.String()
+	if !isValidNumber(s) {
+		e.error(os.NewError("json: invalid number literal " + strconv.Quote(s)))
+	}
+	if quoted {
+		e.string(s)
+	} else {
+		e.WriteString(s)
+	}
+}
+
+func stringEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	if quoted {
+		sb, err := Marshal(v.String())
+		if err != nil {
+			e.error(err)
+		}
+		e.string(string(sb))
+	} else {
+		e.string(v.String())
+	}
+}
+
+func interfaceEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	if v.IsNil() {
+		e.WriteString("null")
+		return
+	}
+	ev := v.Elem()
+	valueEncoder(ev)(e, ev, quoted)
+}
+
+// field holds the precomputed encoding plan for one exported struct
+// field: its JSON name, its encoder, and the tag options that affect
+// whether and how it is written.
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+	quoted    bool
+	encoder   encoderFunc
+}
+
+type structEncoder struct {
+	fields []field
+}
+
+func (se *structEncoder) encode(e *encodeState, v reflect.Value, quoted bool) {
+	e.WriteByte('{')
+	first := true
+	for _, f := range se.fields {
+		fv := v.Field(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if first {
+			first = false
+		} else {
+			e.WriteByte(',')
+		}
+		e.string(f.name)
+		e.WriteByte(':')
+		f.encoder(e, fv, f.quoted)
+	}
+	e.WriteByte('}')
+}
+
+func newStructEncoder(t reflect.Type) encoderFunc {
+	se := &structEncoder{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name, omitEmpty, quoted := sf.Name, false, false
+		if tv := sf.Tag.Get("json"); tv != "" {
+			tagName, opts := parseTag(tv)
+			if isValidTag(tagName) {
+				name = tagName
+			}
+			omitEmpty = opts.Contains("omitempty")
+			quoted = opts.Contains("string")
+		}
+		se.fields = append(se.fields, field{
+			name:      name,
+			index:     i,
+			omitEmpty: omitEmpty,
+			quoted:    quoted,
+			encoder:   typeEncoder(sf.Type),
+		})
+	}
+	return se.encode
+}
+
+type mapEncoder struct {
+	elemEnc encoderFunc
+}
+
+func (me *mapEncoder) encode(e *encodeState, v reflect.Value, quoted bool) {
+	if v.IsNil() {
+		e.WriteString("null")
+		return
+	}
+	e.WriteByte('{')
+	kt := v.Type().Key()
+	keyIsText := kt.Implements(textMarshalerType)
+	keys := v.MapKeys()
+	sv := make(mapKeyValues, len(keys))
+	for i, k := range keys {
+		if keyIsText {
+			m, ok := textMarshaler(k)
+			if !ok {
+				e.error(&UnsupportedTypeError{kt})
+			}
+			b, err := m.MarshalText()
+			if err != nil {
+				e.error(&MarshalerError{kt, err})
+			}
+			sv[i] = mapKeyValue{string(b), v.MapIndex(k)}
+		} else {
+			sv[i] = mapKeyValue{k.String(), v.MapIndex(k)}
+		}
+	}
+	sort.Sort(sv)
+	for i, kv := range sv {
+		if i > 0 {
+			e.WriteByte(',')
+		}
+		e.string(kv.key)
+		e.WriteByte(':')
+		me.elemEnc(e, kv.value, false)
+	}
+	e.WriteByte('}')
+}
+
+func newMapEncoder(t reflect.Type) encoderFunc {
+	kt := t.Key()
+	// A map key is never addressable (it comes from Value.MapKeys), so
+	// only a value-receiver TextMarshaler can ever be dispatched on it;
+	// unlike struct fields, there's no pointer-receiver fallback here.
+	if kt.Kind() != reflect.String && !kt.Implements(textMarshalerType) {
+		return unsupportedTypeEncoder
+	}
+	me := &mapEncoder{typeEncoder(t.Elem())}
+	return me.encode
+}
+
+// newSliceEncoder returns the encoderFunc for a slice type. Other than
+// the []byte special case, slices and arrays share the same encoding
+// (reflect.Value.Len and Index work the same on both, including on a
+// nil slice, which encodes as "[]" rather than "null").
+func newSliceEncoder(t reflect.Type) encoderFunc {
+	if t == byteSliceType {
+		return encodeByteSlice
+	}
+	return newArrayEncoder(t)
+}
+
+func encodeByteSlice(e *encodeState, v reflect.Value, quoted bool) {
+	s := v.Interface().([]byte)
+	e.WriteByte('"')
+	if len(s) < 1024 {
+		// for small buffers, using Encode directly is much faster.
+		dst := make([]byte, base64.StdEncoding.EncodedLen(len(s)))
+		base64.StdEncoding.Encode(dst, s)
+		e.Write(dst)
+	} else {
+		// for large buffers, avoid unnecessary extra temporary
+		// buffer space.
+		enc := base64.NewEncoder(base64.StdEncoding, e)
+		enc.Write(s)
+		enc.Close()
+	}
+	e.WriteByte('"')
+}
+
+type arrayEncoder struct {
+	elemEnc encoderFunc
+}
+
+func (ae *arrayEncoder) encode(e *encodeState, v reflect.Value, quoted bool) {
+	e.WriteByte('[')
+	n := v.Len()
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			e.WriteByte(',')
+		}
+		ae.elemEnc(e, v.Index(i), false)
+	}
+	e.WriteByte(']')
+}
+
+func newArrayEncoder(t reflect.Type) encoderFunc {
+	return (&arrayEncoder{typeEncoder(t.Elem())}).encode
+}
+
+type ptrEncoder struct {
+	elemEnc encoderFunc
+}
+
+func (pe *ptrEncoder) encode(e *encodeState, v reflect.Value, quoted bool) {
+	if v.IsNil() {
+		e.WriteString("null")
+		return
+	}
+	pe.elemEnc(e, v.Elem(), quoted)
+}
+
+func newPtrEncoder(t reflect.Type) encoderFunc {
+	return (&ptrEncoder{typeEncoder(t.Elem())}).encode
+}
@@ -0,0 +1,44 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package json
+
+import (
+	"bytes"
+	"os"
+)
+
+// RawMessage is a raw encoded JSON value. It implements Marshaler and
+// Unmarshaler and can be used to delay JSON decoding or precompute a
+// JSON encoding, for instance to inspect a discriminator field before
+// choosing the concrete type to decode the rest of a message into.
+type RawMessage []byte
+
+// MarshalJSON returns the JSON encoding of m, which is m itself,
+// compacted into a scratch buffer to verify that it holds a legal JSON
+// value.
+func (m RawMessage) MarshalJSON() ([]byte, os.Error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	if err := Compact(&buf, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// UnmarshalJSON sets *m to a copy of data.
+func (m *RawMessage) UnmarshalJSON(data []byte) os.Error {
+	if m == nil {
+		return os.NewError("json.RawMessage: UnmarshalJSON on nil pointer")
+	}
+	*m = append((*m)[0:0], data...)
+	return nil
+}
+
+var (
+	_ Marshaler   = (RawMessage)(nil)
+	_ Unmarshaler = (*RawMessage)(nil)
+)
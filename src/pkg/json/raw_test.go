@@ -0,0 +1,49 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package json
+
+import (
+	"testing"
+)
+
+func TestRawMessageStructField(t *testing.T) {
+	type Envelope struct {
+		Type    string
+		Payload RawMessage
+	}
+
+	e := Envelope{Type: "point", Payload: RawMessage(`{"x":1,"y":2}`)}
+	b, err := Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Type":"point","Payload":{"x":1,"y":2}}`; string(b) != want {
+		t.Fatalf("Marshal = %s, want %s", b, want)
+	}
+
+	var got Envelope
+	if err := Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != "point" || string(got.Payload) != `{"x":1,"y":2}` {
+		t.Fatalf("Unmarshal = %+v", got)
+	}
+}
+
+func TestRawMessageRejectsInvalidJSON(t *testing.T) {
+	if _, err := Marshal(RawMessage(`{not json`)); err == nil {
+		t.Fatal("Marshal(invalid RawMessage): got nil error, want one")
+	}
+}
+
+func TestRawMessageNil(t *testing.T) {
+	b, err := Marshal(RawMessage(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "null" {
+		t.Fatalf("Marshal(nil RawMessage) = %s, want null", b)
+	}
+}
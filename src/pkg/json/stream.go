@@ -0,0 +1,215 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package json
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// An Encoder writes JSON values to an output stream.
+type Encoder struct {
+	w io.Writer
+	e encodeState
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the JSON encoding of v to the stream, followed by a
+// newline character.
+func (enc *Encoder) Encode(v interface{}) os.Error {
+	enc.e.Reset()
+	if err := enc.e.marshal(v); err != nil {
+		return err
+	}
+	enc.e.WriteByte('\n')
+	if _, err := enc.w.Write(enc.e.Bytes()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// A Decoder reads and decodes JSON values from an input stream.
+type Decoder struct {
+	r         io.Reader
+	buf       []byte
+	useNumber bool
+}
+
+// NewDecoder returns a new Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// UseNumber causes the Decoder to unmarshal a JSON number into an
+// interface{} as a Number instead of as a float64.
+func (dec *Decoder) UseNumber() { dec.useNumber = true }
+
+// Decode reads the next JSON-encoded value from its input and stores it
+// in the value pointed to by v.
+func (dec *Decoder) Decode(v interface{}) os.Error {
+	if err := dec.skipSpace(); err != nil {
+		return err
+	}
+	n, err := dec.readValue()
+	if err != nil {
+		return err
+	}
+	item := dec.buf[:n]
+	dec.buf = dec.buf[n:]
+
+	d := new(decodeState)
+	d.useNumber = dec.useNumber
+	d.init(item)
+	return d.unmarshal(v)
+}
+
+// More reports whether there is another value in the stream, that is,
+// whether any input other than whitespace remains. It reports false if
+// reading from the underlying reader fails.
+func (dec *Decoder) More() bool {
+	if dec.skipSpace() != nil {
+		return false
+	}
+	return len(dec.buf) > 0
+}
+
+// Buffered returns a reader of the data remaining in the Decoder's
+// buffer. The reader is valid until the next call to Decode.
+func (dec *Decoder) Buffered() io.Reader {
+	return bytes.NewBuffer(dec.buf)
+}
+
+// skipSpace consumes leading whitespace from dec.buf, filling the
+// buffer from dec.r as necessary.
+func (dec *Decoder) skipSpace() os.Error {
+	for {
+		i := 0
+		for i < len(dec.buf) {
+			switch dec.buf[i] {
+			case ' ', '\t', '\r', '\n':
+				i++
+				continue
+			}
+			break
+		}
+		dec.buf = dec.buf[i:]
+		if len(dec.buf) > 0 {
+			return nil
+		}
+		if err := dec.fill(); err != nil {
+			return err
+		}
+	}
+}
+
+// readValue ensures dec.buf holds a complete JSON value at its front,
+// filling the buffer from dec.r as necessary, and returns its length.
+// It tracks object/array nesting depth and string/escape state to find
+// the end of the value without decoding it.
+func (dec *Decoder) readValue() (int, os.Error) {
+	for {
+		if n, ok := nextValueLen(dec.buf); ok {
+			return n, nil
+		}
+		if err := dec.fill(); err != nil {
+			if err == os.EOF && len(dec.buf) > 0 {
+				// A trailing literal or number with nothing
+				// following it in the stream; what we have is
+				// the whole value.
+				return len(dec.buf), nil
+			}
+			return 0, err
+		}
+	}
+}
+
+// fill reads more data from dec.r into dec.buf.
+func (dec *Decoder) fill() os.Error {
+	tmp := make([]byte, 4096)
+	n, err := dec.r.Read(tmp)
+	if n > 0 {
+		dec.buf = append(dec.buf, tmp[:n]...)
+	}
+	if n == 0 && err != nil {
+		return err
+	}
+	return nil
+}
+
+// nextValueLen reports the length of the JSON value sitting at the
+// front of data, which must hold no leading whitespace. It reports ok
+// as false if data does not yet contain a complete value.
+func nextValueLen(data []byte) (n int, ok bool) {
+	if len(data) == 0 {
+		return 0, false
+	}
+
+	switch data[0] {
+	case '{', '[':
+		depth := 0
+		inString := false
+		escaped := false
+		for i := 0; i < len(data); i++ {
+			c := data[i]
+			if inString {
+				switch {
+				case escaped:
+					escaped = false
+				case c == '\\':
+					escaped = true
+				case c == '"':
+					inString = false
+				}
+				continue
+			}
+			switch c {
+			case '"':
+				inString = true
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1, true
+				}
+			}
+		}
+		return 0, false
+
+	case '"':
+		escaped := false
+		for i := 1; i < len(data); i++ {
+			c := data[i]
+			if escaped {
+				escaped = false
+				continue
+			}
+			if c == '\\' {
+				escaped = true
+				continue
+			}
+			if c == '"' {
+				return i + 1, true
+			}
+		}
+		return 0, false
+
+	default:
+		// A number, true, false, or null runs to the next space or
+		// JSON delimiter.
+		for i := 0; i < len(data); i++ {
+			switch data[i] {
+			case ' ', '\t', '\r', '\n', ',', '}', ']':
+				return i, true
+			}
+		}
+		return 0, false
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2011 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package json
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestEncoderDecoderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	values := []int{1, 2, 3}
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dec := NewDecoder(&buf)
+	for _, want := range values {
+		var got int
+		if !dec.More() {
+			t.Fatalf("More() = false before decoding %d", want)
+		}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Decode = %d, want %d", got, want)
+		}
+	}
+	if dec.More() {
+		t.Error("More() = true after draining the stream")
+	}
+}
+
+func TestDecoderAcrossReads(t *testing.T) {
+	// chunkReader hands back the input one byte at a time, so Decode
+	// must fill its buffer across multiple reads to find a value's end.
+	r := &chunkReader{data: []byte(`{"a":[1,2,{"b":"c\"d"}]} 42`)}
+	dec := NewDecoder(r)
+
+	var v map[string]interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err := dec.Decode(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 42 {
+		t.Errorf("second Decode = %d, want 42", n)
+	}
+}
+
+type chunkReader struct {
+	data []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, os.Error) {
+	if len(r.data) == 0 {
+		return 0, os.EOF
+	}
+	n := copy(p, r.data[:1])
+	r.data = r.data[1:]
+	return n, nil
+}
+
+func TestNextValueLen(t *testing.T) {
+	tests := []struct {
+		in string
+		n  int
+		ok bool
+	}{
+		{`123`, 0, false}, // no trailing delimiter yet
+		{`123 `, 3, true},
+		{`123,`, 3, true},
+		{`"abc"`, 5, true},
+		{`"a\"b"`, 6, true},
+		{`"abc`, 0, false},
+		{`{"a":1}`, 7, true},
+		{`{"a":{"b":1}}`, 13, true},
+		{`[1,2,3]`, 7, true},
+		{`{"a":1} extra`, 7, true},
+		{`{"a":1`, 0, false},
+	}
+	for _, tt := range tests {
+		n, ok := nextValueLen([]byte(tt.in))
+		if n != tt.n || ok != tt.ok {
+			t.Errorf("nextValueLen(%q) = %d, %v, want %d, %v", tt.in, n, ok, tt.n, tt.ok)
+		}
+	}
+}